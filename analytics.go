@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	clicksCollectionName = "clicks"
+
+	clickBufferSize  = 1000
+	clickBatchSize   = 100
+	clickFlushPeriod = 5 * time.Second
+)
+
+// ClickEvent records a single redirect hit for the analytics subsystem.
+type ClickEvent struct {
+	ShortCode string    `bson:"short_code" json:"short_code"`
+	Timestamp time.Time `bson:"timestamp" json:"timestamp"`
+	Referer   string    `bson:"referer,omitempty" json:"referer,omitempty"`
+	UserAgent string    `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	Country   string    `bson:"country,omitempty" json:"country,omitempty"`
+	Subnet    string    `bson:"subnet,omitempty" json:"subnet,omitempty"`
+	IPHash    string    `bson:"ip_hash" json:"ip_hash"`
+}
+
+// MongoBacked is implemented by stores that expose their underlying MongoDB database, so
+// features that fall outside the core Store interface (click analytics, the clicks
+// collection aggregations below) can reach it without the rest of the codebase depending
+// on MongoStore directly. Only the mongo backend supports click analytics today.
+type MongoBacked interface {
+	MongoDatabase() *mongo.Database
+}
+
+// clickTracker buffers click events and flushes them to MongoDB in batches so recording a
+// hit never blocks the redirect hot path on a round-trip to the database.
+type clickTracker struct {
+	col    *mongo.Collection
+	events chan ClickEvent
+}
+
+var tracker *clickTracker
+
+// startClickTracking wires up the click-tracking subsystem if the active store is backed
+// by MongoDB. On any other backend, click tracking is a no-op and recordClick silently
+// does nothing.
+func startClickTracking() {
+	mb, ok := appStore.(MongoBacked)
+	if !ok {
+		logger.Info("storage backend has no MongoDB database, click tracking disabled")
+		return
+	}
+
+	tracker = &clickTracker{
+		col:    mb.MongoDatabase().Collection(clicksCollectionName),
+		events: make(chan ClickEvent, clickBufferSize),
+	}
+
+	go tracker.run()
+
+	logger.Info("click tracking enabled", "batch_size", clickBatchSize, "flush_period", clickFlushPeriod.String())
+}
+
+// recordClick enqueues a click event without blocking; if the buffer is full the event is
+// dropped and logged rather than slowing down the redirect response.
+func recordClick(code string, r *http.Request, subnet string) {
+	if tracker == nil {
+		return
+	}
+
+	event := ClickEvent{
+		ShortCode: code,
+		Timestamp: time.Now(),
+		Referer:   r.Referer(),
+		UserAgent: r.UserAgent(),
+		Country:   lookupCountry(getClientIP(r)),
+		Subnet:    subnet,
+		IPHash:    hashIP(getClientIP(r)),
+	}
+
+	select {
+	case tracker.events <- event:
+	default:
+		logger.Warn("click event buffer full, dropping event", "short_code", code)
+	}
+}
+
+func (t *clickTracker) run() {
+	batch := make([]any, 0, clickBatchSize)
+	ticker := time.NewTicker(clickFlushPeriod)
+	defer ticker.Stop()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if _, err := t.col.InsertMany(ctx, batch); err != nil {
+			logger.Error("error flushing click events", "error", err)
+		}
+		cancel()
+
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case event := <-t.events:
+			batch = append(batch, event)
+			if len(batch) >= clickBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// hashIP derives a stable, non-reversible identifier for an IP so "unique visitors" can be
+// computed without storing raw IPs in the clicks collection.
+func hashIP(ip string) string {
+	sum := sha256.Sum256([]byte(ip))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookupCountry resolves an IP to a country code via the configured CountryLookup (see
+// geoip.go). It returns "" when no GEOIP_COUNTRY_DB is configured or the IP isn't covered,
+// rather than faking a placeholder value.
+func lookupCountry(ip string) string {
+	if countryLookup == nil {
+		return ""
+	}
+
+	country, ok := countryLookup.Lookup(ip)
+	if !ok {
+		return ""
+	}
+	return country
+}
+
+// statsByCodeHandler serves GET /api/stats/{code}: total clicks, unique visitors (by hashed
+// IP), an hourly click histogram, and the top referers/countries, all computed with a Mongo
+// aggregation pipeline over the clicks collection.
+func statsByCodeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	code := strings.TrimPrefix(r.URL.Path, "/api/stats/")
+	if code == "" || !isAlphanumeric(code) {
+		http.Error(w, "invalid short code", 400)
+		return
+	}
+
+	mb, ok := appStore.(MongoBacked)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(map[string]any{
+			"error": "click analytics are not supported by the current storage backend",
+		})
+		return
+	}
+
+	col := mb.MongoDatabase().Collection(clicksCollectionName)
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	reqID := requestIDFromContext(r.Context())
+
+	total, err := col.CountDocuments(ctx, bson.M{"short_code": code})
+	if err != nil {
+		logger.Error("error counting clicks", "request_id", reqID, "short_code", code, "error", err)
+		http.Error(w, "internal server error", 500)
+		return
+	}
+
+	uniqueVisitors, err := aggregateCount(ctx, col, code, "$ip_hash")
+	if err != nil {
+		logger.Error("error counting unique visitors", "request_id", reqID, "short_code", code, "error", err)
+	}
+
+	histogram, err := aggregateHistogram(ctx, col, code)
+	if err != nil {
+		logger.Error("error building histogram", "request_id", reqID, "short_code", code, "error", err)
+	}
+
+	topReferers, err := aggregateTop(ctx, col, code, "$referer", 5)
+	if err != nil {
+		logger.Error("error aggregating top referers", "request_id", reqID, "short_code", code, "error", err)
+	}
+
+	resp := map[string]any{
+		"short_code":      code,
+		"total_clicks":    total,
+		"unique_visitors": uniqueVisitors,
+		"histogram":       histogram,
+		"top_referers":    topReferers,
+	}
+
+	// Only report a country breakdown when a CountryLookup is actually configured (see
+	// geoip.go); otherwise every event's country is "" and the dimension would be noise.
+	if countryLookup != nil {
+		topCountries, err := aggregateTop(ctx, col, code, "$country", 5)
+		if err != nil {
+			logger.Error("error aggregating top countries", "request_id", reqID, "short_code", code, "error", err)
+		}
+		resp["top_countries"] = topCountries
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+func aggregateCount(ctx context.Context, col *mongo.Collection, code, field string) (int, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"short_code": code}},
+		{"$group": bson.M{"_id": field}},
+		{"$count": "count"},
+	}
+
+	cursor, err := col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []bson.M
+	if err := cursor.All(ctx, &result); err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+
+	count, _ := result[0]["count"].(int32)
+	return int(count), nil
+}
+
+// aggregateHistogram buckets clicks by hour (UTC), e.g. "2026-07-27T14:00".
+func aggregateHistogram(ctx context.Context, col *mongo.Collection, code string) (map[string]int, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"short_code": code}},
+		{"$group": bson.M{
+			"_id":   bson.M{"$dateToString": bson.M{"format": "%Y-%m-%dT%H:00", "date": "$timestamp"}},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+
+	cursor, err := col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []bson.M
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	histogram := make(map[string]int, len(result))
+	for _, row := range result {
+		bucket, _ := row["_id"].(string)
+		count, _ := row["count"].(int32)
+		histogram[bucket] = int(count)
+	}
+
+	return histogram, nil
+}
+
+func aggregateTop(ctx context.Context, col *mongo.Collection, code, field string, limit int) ([]map[string]any, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"short_code": code}},
+		{"$group": bson.M{"_id": field, "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.M{"count": -1}},
+		{"$limit": limit},
+	}
+
+	cursor, err := col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []bson.M
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	top := make([]map[string]any, 0, len(result))
+	for _, row := range result {
+		top = append(top, map[string]any{
+			"value": row["_id"],
+			"count": row["count"],
+		})
+	}
+
+	return top, nil
+}
+
+// clicksExportHandler serves POST /api/clicks/export, streaming every click event as
+// newline-delimited JSON for offline analysis. An optional "code" query param restricts the
+// export to a single short code. Protected by the same admin bearer token as /api/cert
+// (isAuthorizedAdmin), since this dumps otherwise-private analytics data (referers, user
+// agents, IP hashes), not public API surface.
+func clicksExportHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAuthorizedAdmin(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mb, ok := appStore.(MongoBacked)
+	if !ok {
+		http.Error(w, "click analytics are not supported by the current storage backend", http.StatusNotImplemented)
+		return
+	}
+
+	filter := bson.M{}
+	if code := r.URL.Query().Get("code"); code != "" {
+		filter["short_code"] = code
+	}
+
+	col := mb.MongoDatabase().Collection(clicksCollectionName)
+	cursor, err := col.Find(r.Context(), filter)
+	if err != nil {
+		logger.Error("error exporting clicks", "request_id", requestIDFromContext(r.Context()), "error", err)
+		http.Error(w, "internal server error", 500)
+		return
+	}
+	defer cursor.Close(r.Context())
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+
+	for cursor.Next(r.Context()) {
+		var event ClickEvent
+		if cursor.Decode(&event) != nil {
+			continue
+		}
+
+		if encoder.Encode(event) != nil {
+			return
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// CountryLookup resolves a client IP to a country code. It's pluggable the same way
+// URLScreener is: nil means disabled, and callers degrade by omitting country data rather
+// than faking a result.
+type CountryLookup interface {
+	Lookup(ip string) (country string, ok bool)
+}
+
+// countryLookup is the configured CountryLookup, wired up by startCountryLookup; nil until
+// GEOIP_COUNTRY_DB is set, in which case country data is simply left out of analytics.
+var countryLookup CountryLookup
+
+// startCountryLookup loads a local IPv4-range-to-country database if GEOIP_COUNTRY_DB is
+// set. It's safe to call even when unconfigured: countryLookup stays nil and lookupCountry
+// returns "" instead of a fake placeholder.
+func startCountryLookup() {
+	path := os.Getenv("GEOIP_COUNTRY_DB")
+	if path == "" {
+		logger.Info("GeoIP country lookup disabled (GEOIP_COUNTRY_DB not set)")
+		return
+	}
+
+	db, err := loadCSVCountryDB(path)
+	if err != nil {
+		logger.Warn("failed to load GeoIP country database, country lookup disabled", "path", path, "error", err)
+		return
+	}
+
+	countryLookup = db
+	logger.Info("GeoIP country lookup enabled", "path", path, "ranges", len(db.ranges))
+}
+
+type ipRange struct {
+	start, end uint32
+	country    string
+}
+
+// csvCountryDB is a CountryLookup backed by a sorted list of IPv4 ranges loaded from a local
+// CSV file (start_ip,end_ip,country_code per line), the same "load a local file into memory"
+// shape as blocklistScreener, but for country resolution instead of a domain blocklist.
+type csvCountryDB struct {
+	ranges []ipRange
+}
+
+func loadCSVCountryDB(path string) (*csvCountryDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ranges []ipRange
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			continue
+		}
+
+		start, err := ipv4ToUint32(strings.TrimSpace(fields[0]))
+		if err != nil {
+			continue
+		}
+		end, err := ipv4ToUint32(strings.TrimSpace(fields[1]))
+		if err != nil {
+			continue
+		}
+
+		ranges = append(ranges, ipRange{start: start, end: end, country: strings.TrimSpace(fields[2])})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no valid ranges found in %s", path)
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	return &csvCountryDB{ranges: ranges}, nil
+}
+
+func ipv4ToUint32(s string) (uint32, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return 0, fmt.Errorf("invalid IP: %q", s)
+	}
+	v4 := ip.To4()
+	if v4 == nil {
+		return 0, fmt.Errorf("not an IPv4 address: %q", s)
+	}
+	return binary.BigEndian.Uint32(v4), nil
+}
+
+// Lookup finds the range containing ip via binary search over the sorted range starts.
+func (db *csvCountryDB) Lookup(ip string) (string, bool) {
+	addr, err := ipv4ToUint32(ip)
+	if err != nil {
+		return "", false
+	}
+
+	i := sort.Search(len(db.ranges), func(i int) bool { return db.ranges[i].start > addr })
+	if i == 0 {
+		return "", false
+	}
+
+	r := db.ranges[i-1]
+	if addr >= r.start && addr <= r.end {
+		return r.country, true
+	}
+	return "", false
+}
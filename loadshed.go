@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/load"
+)
+
+const (
+	defaultLoadSoft = 2.0
+	defaultLoadHard = 4.0
+
+	loadSampleInterval = 5 * time.Second
+
+	// minEffectiveLimit is the floor effectiveLimit() degrades to as load approaches the
+	// hard threshold, so the service keeps accepting a trickle of writes instead of
+	// instantly going from maxURLsPerSubnet to zero.
+	minEffectiveLimit = 1
+)
+
+// loadStatus is a snapshot of host load sampled by the background monitor.
+type loadStatus struct {
+	Load1          float64 `json:"load1"`
+	Load5          float64 `json:"load5"`
+	NormalizedLoad float64 `json:"normalized_load"` // Load1 / NumCPU
+	Goroutines     int     `json:"goroutines"`
+	NumCPU         int     `json:"num_cpu"`
+	EffectiveLimit int     `json:"effective_limit"`
+	Shedding       bool    `json:"shedding"` // true jika /api/shorten sedang menolak semua request
+}
+
+var (
+	loadSoft = defaultLoadSoft
+	loadHard = defaultLoadHard
+
+	loadMu   sync.RWMutex
+	lastLoad loadStatus
+)
+
+// loadLoadShedConfig reads LOAD_SOFT and LOAD_HARD from the environment (normalized per CPU),
+// falling back to the package defaults when unset or invalid.
+func loadLoadShedConfig() {
+	if v := os.Getenv("LOAD_SOFT"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
+			loadSoft = f
+		} else {
+			logger.Warn("invalid LOAD_SOFT, using default", "value", v, "default", defaultLoadSoft)
+		}
+	}
+
+	if v := os.Getenv("LOAD_HARD"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > loadSoft {
+			loadHard = f
+		} else {
+			logger.Warn("invalid LOAD_HARD, using default", "value", v, "default", defaultLoadHard)
+		}
+	}
+}
+
+// startLoadMonitor samples Load1/Load5 and goroutine count every 5s in the background,
+// the same periodic-ticker pattern as the cleanup jobs.
+func startLoadMonitor() {
+	loadLoadShedConfig()
+
+	sampleLoad()
+
+	ticker := time.NewTicker(loadSampleInterval)
+	go func() {
+		for {
+			<-ticker.C
+			sampleLoad()
+		}
+	}()
+
+	logger.Info("load monitor started", "soft", loadSoft, "hard", loadHard, "num_cpu", runtime.NumCPU())
+}
+
+func sampleLoad() {
+	avg, err := load.Avg()
+	if err != nil {
+		logger.Warn("failed to read load average", "error", err)
+		return
+	}
+
+	numCPU := runtime.NumCPU()
+	normalized := avg.Load1 / float64(numCPU)
+
+	status := loadStatus{
+		Load1:          avg.Load1,
+		Load5:          avg.Load5,
+		NormalizedLoad: normalized,
+		Goroutines:     runtime.NumGoroutine(),
+		NumCPU:         numCPU,
+		EffectiveLimit: effectiveLimitFor(normalized),
+		Shedding:       normalized >= loadHard,
+	}
+
+	loadMu.Lock()
+	lastLoad = status
+	loadMu.Unlock()
+}
+
+// effectiveLimitFor linearly reduces maxURLsPerSubnet to minEffectiveLimit as normalized
+// load moves from loadSoft to loadHard. Below soft it's unchanged; at or above hard the
+// caller should reject outright rather than rely on this floor.
+func effectiveLimitFor(normalizedLoad float64) int {
+	if normalizedLoad <= loadSoft {
+		return maxURLsPerSubnet
+	}
+	if normalizedLoad >= loadHard {
+		return minEffectiveLimit
+	}
+
+	span := loadHard - loadSoft
+	frac := (normalizedLoad - loadSoft) / span
+	limit := float64(maxURLsPerSubnet) - frac*float64(maxURLsPerSubnet-minEffectiveLimit)
+
+	if limit < minEffectiveLimit {
+		return minEffectiveLimit
+	}
+	return int(limit)
+}
+
+// currentLoadStatus returns the most recent load sample.
+func currentLoadStatus() loadStatus {
+	loadMu.RLock()
+	defer loadMu.RUnlock()
+	return lastLoad
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// logger is the package-wide structured logger, initialized by initLogger before anything
+// else starts up so every later log line is JSON with leveled events and fields instead of
+// unstructured text to stderr.
+var logger *slog.Logger
+
+type requestIDKeyType struct{}
+
+var requestIDKey requestIDKeyType
+
+const (
+	defaultLogMaxSizeMB  = 100
+	defaultLogMaxBackups = 5
+)
+
+// initLogger sets up a JSON slog.Logger. If LOG_FILE_PATH is set, output goes to that file
+// with size-based rotation (LOG_MAX_SIZE_MB, LOG_MAX_BACKUPS, LOG_COMPRESS) so a long-running
+// deployment doesn't fill the disk; otherwise it logs to stderr, same as before.
+func initLogger() {
+	var writer = os.Stderr
+
+	handler := slog.NewJSONHandler(writer, nil)
+
+	if logPath := os.Getenv("LOG_FILE_PATH"); logPath != "" {
+		maxSizeMB := defaultLogMaxSizeMB
+		if v := os.Getenv("LOG_MAX_SIZE_MB"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				maxSizeMB = n
+			}
+		}
+
+		maxBackups := defaultLogMaxBackups
+		if v := os.Getenv("LOG_MAX_BACKUPS"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+				maxBackups = n
+			}
+		}
+
+		rotator := &lumberjack.Logger{
+			Filename:   logPath,
+			MaxSize:    maxSizeMB,
+			MaxBackups: maxBackups,
+			Compress:   os.Getenv("LOG_COMPRESS") == "true",
+		}
+
+		handler = slog.NewJSONHandler(rotator, nil)
+	}
+
+	logger = slog.New(handler)
+	slog.SetDefault(logger)
+}
+
+// fatal logs msg at error level with args and exits, replacing the log.Fatal calls the
+// service used before it had a structured logger.
+func fatal(msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+// newRequestID generates a short random hex correlation ID for a request.
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// requestIDFromContext returns the request's correlation ID, or "" outside a request
+// (e.g. the background cleanup jobs).
+func requestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// statusRecorder wraps http.ResponseWriter to capture the status code written by the
+// handler, so requestLogMiddleware can log it after the fact.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// requestLogMiddleware assigns each request a correlation ID (reusing X-Request-ID if the
+// client sent one), threads it through the request context so downstream store calls and
+// cleanup errors can be correlated, and logs method/path/status/duration/client_ip/subnet
+// once the handler returns.
+func requestLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		reqID := r.Header.Get("X-Request-ID")
+		if reqID == "" {
+			reqID = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", reqID)
+
+		r = r.WithContext(withRequestID(r.Context(), reqID))
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		logger.Info("request",
+			"event", "request_handled",
+			"request_id", reqID,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", getClientIP(r),
+			"subnet", getClientSubnet(r),
+		)
+	})
+}
@@ -2,24 +2,18 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"math/rand"
 	"net"
 	"net/http"
 	"strings"
-	"sync"
 	"time"
 
 	"os"
 
 	"github.com/joho/godotenv"
-
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
-	"go.mongodb.org/mongo-driver/v2/mongo/options"
-	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
 )
 
 const (
@@ -31,6 +25,18 @@ const (
 	// Rate limiting constants
 	maxURLsPerSubnet = 10 // Maksimal 10 shortlink per subnet /24
 	cooldownHours    = 24 // Cooldown 24 jam
+
+	// Short code generation defaults, overridable via CODE_LENGTH / CODE_ALPHABET
+	defaultCodeLength   = 6
+	defaultCodeAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	maxAliasLength = 32
+
+	// Cleanup untuk rate limit data & record kedaluwarsa (khusus backend yang tidak punya TTL native)
+	rateLimitCleanupInterval = 1 * time.Hour
+	expiryCleanupInterval    = 1 * time.Hour
+
+	maxGenCodeRetries = 5
 )
 
 type URLData struct {
@@ -40,73 +46,86 @@ type URLData struct {
 	ExpiresAt     time.Time `bson:"expires_at" json:"expires_at"`
 	CreatorIP     string    `bson:"creator_ip,omitempty" json:"creator_ip,omitempty"`
 	CreatorSubnet string    `bson:"creator_subnet,omitempty" json:"creator_subnet,omitempty"`
-}
-
-type CacheItem struct {
-	OriginalURL string
-	ExpiresAt   time.Time
-}
-
-type RateLimitInfo struct {
-	Count     int       // Jumlah URL yang dibuat
-	FirstSeen time.Time // Waktu pertama kali membuat URL
-	Cooldown  time.Time // Waktu cooldown berakhir (jika melebihi limit)
+	BlockedAt     time.Time `bson:"blocked_at,omitempty" json:"blocked_at,omitempty"`
 }
 
 var (
-	client *mongo.Client
-	col    *mongo.Collection
-	cache  = map[string]CacheItem{}
+	// appStore is the active storage backend, selected at startup by newStore.
+	appStore Store
 
-	// Rate limiting berdasarkan subnet
-	rateLimitMap   = make(map[string]*RateLimitInfo)
-	rateLimitMutex = &sync.RWMutex{}
+	// Short code generation, configurable via env
+	codeLength   = defaultCodeLength
+	codeAlphabet = defaultCodeAlphabet
 
-	// Cleanup untuk rate limit data
-	rateLimitCleanupInterval = 1 * time.Hour
+	// Path prefix yang tidak boleh dipakai sebagai alias karena bentrok dengan route yang ada
+	reservedAliases = map[string]bool{
+		"api":    true,
+		"health": true,
+	}
 )
 
 func main() {
+	initLogger()
+
 	// Load .env file
 	err := godotenv.Load()
 	if err != nil {
-			log.Println("Warning: .env file not found, using environment variables")
-	}
-
-	rand.Seed(time.Now().UnixNano())
-	connectMongo()
-	initialCleanup()        // Pembersihan pertama kali
-	startPeriodicCleanup()  // Pembersihan periodik setiap 1 jam
-	startRateLimitCleanup() // Cleanup data rate limiting
-	startServer()
-}
-
-func connectMongo() {
-	uri := os.Getenv("MONGODB_URI")  
-	if uri == "" {
-			log.Fatal("MONGODB_URI not set in .env file or environment")
+		logger.Warn(".env file not found, using environment variables")
 	}
 
-	opts := options.Client().
-		ApplyURI(uri).
-		SetServerSelectionTimeout(10 * time.Second)
+	loadCodeConfig()
 
-	c, err := mongo.Connect(opts)
+	s, err := newStore(context.Background())
 	if err != nil {
-		log.Fatal("MongoDB connection failed: ", err)
+		fatal("failed to initialize storage backend", "error", err)
 	}
+	appStore = s
+	logger.Info("storage backend initialized", "backend", storageBackend)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	startClickTracking()
+	startLoadMonitor()
+	startURLScreening()
+	startCountryLookup()
 
-	if err := c.Ping(ctx, readpref.Primary()); err != nil {
-		log.Fatal("MongoDB ping failed: ", err)
+	initialCleanup()            // Pembersihan pertama kali
+	startPeriodicCleanup()      // Pembersihan periodik setiap 1 jam
+	startRateLimitCleanup()     // Cleanup data rate limiting
+	startReportTrackerCleanup() // Cleanup data report tracker
+	startServer()
+}
+
+// loadCodeConfig reads CODE_LENGTH and CODE_ALPHABET from the environment, falling back
+// to the defaults when unset or invalid.
+func loadCodeConfig() {
+	if v := os.Getenv("CODE_LENGTH"); v != "" {
+		if n, err := parsePositiveInt(v); err == nil {
+			codeLength = n
+		} else {
+			logger.Warn("invalid CODE_LENGTH, using default", "value", v, "default", defaultCodeLength)
+		}
 	}
 
-	client = c
-	col = client.Database(dbName).Collection(collectionName)
+	if v := os.Getenv("CODE_ALPHABET"); v != "" {
+		if len(v) < 1 || len(v) > 256 {
+			logger.Warn("invalid CODE_ALPHABET, using default", "length", len(v), "reason", "must be 1-256 bytes")
+		} else {
+			codeAlphabet = v
+		}
+	}
+}
 
-	log.Println("MongoDB connected successfully")
+func parsePositiveInt(s string) (int, error) {
+	n := 0
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("invalid digit: %q", s)
+		}
+		n = n*10 + int(c-'0')
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("must be positive: %q", s)
+	}
+	return n, nil
 }
 
 func getClientIP(r *http.Request) string {
@@ -151,214 +170,78 @@ func getClientSubnet(r *http.Request) string {
 	return ipStr
 }
 
-func checkRateLimit(subnet string) (bool, *RateLimitInfo, error) {
-	rateLimitMutex.Lock()
-	defer rateLimitMutex.Unlock()
-
-	now := time.Now()
-
-	// Cek apakah subnet sudah ada di memory cache
-	if info, exists := rateLimitMap[subnet]; exists {
-		// Reset count jika sudah lewat 24 jam dari first seen
-		if now.Sub(info.FirstSeen) >= 24*time.Hour {
-			info.Count = 0
-			info.FirstSeen = now
-			info.Cooldown = time.Time{}
-		}
-
-		// Cek apakah dalam cooldown
-		if !info.Cooldown.IsZero() && now.Before(info.Cooldown) {
-			return false, info, nil
-		}
-
-		// Reset cooldown jika sudah lewat
-		if !info.Cooldown.IsZero() && now.After(info.Cooldown) {
-			info.Cooldown = time.Time{}
-		}
-
-		// Cek apakah sudah mencapai limit
-		if info.Count >= maxURLsPerSubnet {
-			// Set cooldown 24 jam
-			info.Cooldown = now.Add(cooldownHours * time.Hour)
-			return false, info, nil
-		}
-
-		return true, info, nil
-	}
-
-	// Subnet tidak ada di memory cache, cek di database
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Hitung berapa banyak URL yang dibuat oleh subnet ini dalam 24 jam terakhir
-	oneDayAgo := now.Add(-24 * time.Hour)
-
-	count, err := col.CountDocuments(ctx, bson.M{
-		"creator_subnet": subnet,
-		"created_at":     bson.M{"$gte": oneDayAgo},
-	})
-
-	if err != nil {
-		return false, nil, err
-	}
-
-	// Buat entry baru di memory cache
-	rateLimitMap[subnet] = &RateLimitInfo{
-		Count:     int(count),
-		FirstSeen: now,
-		Cooldown:  time.Time{},
-	}
-
-	// Cek apakah sudah mencapai limit berdasarkan data database
-	if int(count) >= maxURLsPerSubnet {
-		rateLimitMap[subnet].Cooldown = now.Add(cooldownHours * time.Hour)
-		return false, rateLimitMap[subnet], nil
-	}
-
-	return true, rateLimitMap[subnet], nil
-}
-
-func incrementRateLimit(subnet string) {
-	rateLimitMutex.Lock()
-	defer rateLimitMutex.Unlock()
-
-	if info, exists := rateLimitMap[subnet]; exists {
-		info.Count++
-
-		// Jika mencapai limit, set cooldown
-		if info.Count >= maxURLsPerSubnet {
-			info.Cooldown = time.Now().Add(cooldownHours * time.Hour)
-		}
+func startRateLimitCleanup() {
+	cleaner, ok := appStore.(RateLimitCleaner)
+	if !ok {
+		logger.Info("storage backend expires rate limit entries natively, skipping periodic rate limit cleanup")
+		return
 	}
-}
 
-func startRateLimitCleanup() {
 	ticker := time.NewTicker(rateLimitCleanupInterval)
 
 	go func() {
 		for {
 			<-ticker.C
-			cleanupRateLimitMap()
+			cleaner.CleanupRateLimits()
 		}
 	}()
 
-	log.Println("Rate limit cleanup scheduled (every 1 hour)")
+	logger.Info("rate limit cleanup scheduled", "interval", rateLimitCleanupInterval.String())
 }
 
-func cleanupRateLimitMap() {
-	rateLimitMutex.Lock()
-	defer rateLimitMutex.Unlock()
-
-	now := time.Now()
-	removedCount := 0
-
-	for subnet, info := range rateLimitMap {
-		if now.Sub(info.FirstSeen) > 24*time.Hour {
-			delete(rateLimitMap, subnet)
-			removedCount++
-		}
+func initialCleanup() {
+	cleaner, ok := appStore.(ExpiryCleaner)
+	if !ok {
+		logger.Info("storage backend expires records natively, skipping initial cleanup")
+		return
 	}
 
-	if removedCount > 0 {
-		log.Printf("Rate limit cleanup: removed %d old subnet entries", removedCount)
-	}
-}
-
-func initialCleanup() {
-	log.Println("Running initial cleanup...")
+	logger.Info("running initial cleanup")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	now := time.Now()
-
-	result, err := col.DeleteMany(ctx, bson.M{
-		"expires_at": bson.M{"$lt": now},
-	})
-
+	n, err := cleaner.CleanupExpired(ctx)
 	if err != nil {
-		log.Printf("Error during initial cleanup: %v", err)
+		logger.Error("initial cleanup failed", "error", err)
 		return
 	}
 
-	log.Printf("Initial cleanup complete. Deleted %d expired records", result.DeletedCount)
-
-	loadActiveCache()
+	logger.Info("initial cleanup complete", "deleted", n)
 }
 
-func loadActiveCache() {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
-
-	now := time.Now()
-
-	cursor, err := col.Find(ctx, bson.M{
-		"expires_at": bson.M{"$gt": now},
-	})
-
-	if err != nil {
-		log.Printf("Error loading cache: %v", err)
+func startPeriodicCleanup() {
+	cleaner, ok := appStore.(ExpiryCleaner)
+	if !ok {
 		return
 	}
-	defer cursor.Close(ctx)
-
-	for cursor.Next(ctx) {
-		var u URLData
-		if cursor.Decode(&u) != nil {
-			continue
-		}
-
-		cache[u.ShortCode] = CacheItem{
-			OriginalURL: u.Original,
-			ExpiresAt:   u.ExpiresAt,
-		}
-	}
 
-	log.Printf("Loaded %d active items to cache", len(cache))
-}
-
-func startPeriodicCleanup() {
-	ticker := time.NewTicker(1 * time.Hour)
+	ticker := time.NewTicker(expiryCleanupInterval)
 
 	go func() {
 		for {
 			<-ticker.C
-			performCleanup()
+			performCleanup(cleaner)
 		}
 	}()
 
-	log.Println("Periodic cleanup scheduled (every 1 hour)")
+	logger.Info("periodic cleanup scheduled", "interval", expiryCleanupInterval.String())
 }
 
-func performCleanup() {
-	log.Println("Running periodic cleanup...")
+func performCleanup(cleaner ExpiryCleaner) {
+	logger.Info("running periodic cleanup")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	now := time.Now()
-
-	result, err := col.DeleteMany(ctx, bson.M{
-		"expires_at": bson.M{"$lt": now},
-	})
-
+	n, err := cleaner.CleanupExpired(ctx)
 	if err != nil {
-		log.Printf("Error during periodic cleanup: %v", err)
+		logger.Error("periodic cleanup failed", "error", err)
 		return
 	}
 
-	deletedCount := result.DeletedCount
-
-	cleanedCacheCount := 0
-	for code, item := range cache {
-		if now.After(item.ExpiresAt) {
-			delete(cache, code)
-			cleanedCacheCount++
-		}
-	}
-
-	if deletedCount > 0 || cleanedCacheCount > 0 {
-		log.Printf("Cleanup complete. Database: %d deleted, Cache: %d cleaned", deletedCount, cleanedCacheCount)
+	if n > 0 {
+		logger.Info("periodic cleanup complete", "deleted", n)
 	}
 }
 
@@ -369,15 +252,26 @@ func startServer() {
 	mux.HandleFunc("/api/list", listHandler)
 	mux.HandleFunc("/health", healthHandler)
 	mux.HandleFunc("/api/stats", statsHandler)
+	mux.HandleFunc("/api/stats/", statsByCodeHandler)
+	mux.HandleFunc("/api/clicks/export", clicksExportHandler)
+	mux.HandleFunc("/api/report", reportHandler)
+	mux.HandleFunc("/api/cert", certHandler)
 
 	mux.HandleFunc("/", mainHandler)
 
-	log.Println("Server running at http://localhost:5000")
-	log.Println("Cleanup scheduled every 1 hour")
-	log.Printf("Rate limit: %d URLs per subnet (/24) per day", maxURLsPerSubnet)
+	logger.Info("cleanup scheduled every 1 hour")
+	logger.Info("rate limit configured", "max_per_subnet", maxURLsPerSubnet, "window", "/24 per day")
+
+	handler := requestLogMiddleware(mux)
+
+	if maybeStartTLSServer(handler) {
+		return
+	}
+
+	logger.Info("server starting", "addr", serverAddr)
 
-	if err := http.ListenAndServe(serverAddr, mux); err != nil {
-		log.Fatal("Server failed to start: ", err)
+	if err := http.ListenAndServe(serverAddr, handler); err != nil {
+		fatal("server failed to start", "error", err)
 	}
 }
 
@@ -389,7 +283,7 @@ func mainHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(path) == 6 && isAlphanumeric(path) {
+	if len(path) >= 1 && len(path) <= maxRoutablePathLen() && isValidShortCodePath(path) {
 		redirectHandler(path, w, r)
 		return
 	}
@@ -398,59 +292,119 @@ func mainHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func isAlphanumeric(s string) bool {
-	if len(s) != 6 {
+	if s == "" {
 		return false
 	}
 
-	hasLetter := false
-	hasDigit := false
-
 	for _, c := range s {
-		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
-			hasLetter = true
-		} else if c >= '0' && c <= '9' {
-			hasDigit = true
-		} else {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
 			return false // Karakter tidak valid
 		}
 	}
 
-	// Harus memiliki minimal 1 huruf dan 1 angka
-	return hasLetter && hasDigit
+	return true
+}
+
+// maxRoutablePathLen is the longest path segment mainHandler will consider as a short code:
+// aliases are capped at maxAliasLength, but a generated code can be longer if CODE_LENGTH
+// says so, so the route gate has to cover whichever is bigger.
+func maxRoutablePathLen() int {
+	if codeLength > maxAliasLength {
+		return codeLength
+	}
+	return maxAliasLength
+}
+
+// isValidShortCodePath reports whether s could be a short code this server could have
+// handed out: either a generated code drawn from the runtime codeAlphabet, or an alias
+// (always alphanumeric, enforced in shortenHandler). Gating on the hardcoded alphanumeric
+// set alone would 404 every generated code once CODE_ALPHABET includes non-alphanumeric
+// characters.
+func isValidShortCodePath(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, c := range s {
+		if !strings.ContainsRune(codeAlphabet, c) && !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+
+	return true
 }
 
 func shortenHandler(w http.ResponseWriter, r *http.Request) {
 	clientIP := getClientIP(r)
 	clientSubnet := getClientSubnet(r)
 
-	// Check rate limit berdasarkan subnet
-	allowed, rateInfo, err := checkRateLimit(clientSubnet)
+	load := currentLoadStatus()
+	if load.Shedding {
+		w.Header().Set("Retry-After", "30")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]any{
+			"success": false,
+			"error":   "server_overloaded",
+			"message": fmt.Sprintf("Normalized load %.2f exceeds hard threshold %.2f, shedding writes", load.NormalizedLoad, loadHard),
+		})
+		return
+	}
+
+	effectiveLimit := load.EffectiveLimit
+	if effectiveLimit == 0 {
+		effectiveLimit = maxURLsPerSubnet // monitor belum sempat sampling, jangan batasi
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// Check rate limit berdasarkan subnet. This check and the IncrRateLimit call below it
+	// (after a successful insert) aren't atomic, so two concurrent requests from the same
+	// subnet can both pass this check before either increments — the limit is best-effort
+	// under concurrency, same as the original rateLimitMap design.
+	count, resetAt, err := appStore.CountBySubnetSince(ctx, clientSubnet, time.Now().Add(-24*time.Hour))
 	if err != nil {
-		log.Printf("Error checking rate limit: %v", err)
+		logger.Error("error checking rate limit", "request_id", requestIDFromContext(ctx), "error", err)
 		http.Error(w, "internal server error", 500)
 		return
 	}
 
-	if !allowed {
+	if count >= effectiveLimit {
+		cooldownRemaining := cooldownHours * time.Hour
+		if !resetAt.IsZero() {
+			if remaining := time.Until(resetAt); remaining > 0 {
+				cooldownRemaining = remaining
+			} else {
+				cooldownRemaining = 0
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
+		if effectiveLimit < maxURLsPerSubnet {
+			w.Header().Set("Retry-After", "30")
+		}
 		w.WriteHeader(http.StatusTooManyRequests)
 
-		remainingTime := rateInfo.Cooldown.Sub(time.Now())
-		json.NewEncoder(w).Encode(map[string]any{
+		resp := map[string]any{
 			"success":            false,
 			"error":              "rate_limit_exceeded",
-			"message":            fmt.Sprintf("Subnet %s telah membuat %d shortlink hari ini. Cooldown %s", clientSubnet, maxURLsPerSubnet, formatDuration(remainingTime)),
-			"limit":              maxURLsPerSubnet,
+			"message":            fmt.Sprintf("Subnet %s telah membuat %d shortlink hari ini. Cooldown %d jam", clientSubnet, effectiveLimit, cooldownHours),
+			"limit":              effectiveLimit,
 			"subnet":             clientSubnet,
 			"client_ip":          clientIP,
-			"cooldown_until":     rateInfo.Cooldown.Format(time.RFC3339),
-			"cooldown_remaining": formatDuration(remainingTime),
-		})
+			"cooldown_remaining": formatDuration(cooldownRemaining),
+		}
+		if !resetAt.IsZero() {
+			resp["cooldown_until"] = resetAt.Format(time.RFC3339)
+		}
+		json.NewEncoder(w).Encode(resp)
 		return
 	}
 
 	var body struct {
-		URL string `json:"url"`
+		URL   string `json:"url"`
+		Alias string `json:"alias"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "bad request", 400)
@@ -462,33 +416,52 @@ func shortenHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	code := genCode(6)
-	now := time.Now()
-	exp := now.Add(expiryDays * 24 * time.Hour)
+	if body.Alias != "" && (len(body.Alias) > maxAliasLength || !isAlphanumeric(body.Alias) || reservedAliases[strings.ToLower(body.Alias)]) {
+		http.Error(w, "invalid alias", 400)
+		return
+	}
 
-	data := URLData{
-		Original:      body.URL,
-		ShortCode:     code,
-		CreatedAt:     now,
-		ExpiresAt:     exp,
-		CreatorIP:     clientIP,
-		CreatorSubnet: clientSubnet,
+	if screener != nil {
+		if blocked, categories, err := screener.Screen(ctx, body.URL); err != nil {
+			logger.Error("error screening URL", "request_id", requestIDFromContext(ctx), "url", body.URL, "error", err)
+		} else if blocked {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(422)
+			json.NewEncoder(w).Encode(map[string]any{
+				"success":    false,
+				"error":      "unsafe_url",
+				"categories": categories,
+			})
+			return
+		}
 	}
 
-	ctx, c := context.WithTimeout(context.Background(), 5*time.Second)
-	defer c()
-	_, err = col.InsertOne(ctx, data)
+	now := time.Now()
+	exp := now.Add(expiryDays * 24 * time.Hour)
 
+	code, err := insertShortURL(ctx, body.URL, body.Alias, clientIP, clientSubnet, now, exp)
 	if err != nil {
-		log.Printf("Error inserting to DB: %v", err)
+		if errors.Is(err, ErrCodeTaken) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]any{
+				"success": false,
+				"error":   "alias_taken",
+				"message": fmt.Sprintf("Alias %q sudah dipakai", body.Alias),
+			})
+			return
+		}
+
+		logger.Error("error inserting short URL", "request_id", requestIDFromContext(ctx), "error", err)
 		http.Error(w, "database error", 500)
 		return
 	}
 
-	cache[code] = CacheItem{body.URL, exp}
-
 	// Increment rate limit counter berdasarkan subnet
-	incrementRateLimit(clientSubnet)
+	newCount, err := appStore.IncrRateLimit(ctx, clientSubnet, 24*time.Hour)
+	if err != nil {
+		logger.Error("error incrementing rate limit", "request_id", requestIDFromContext(ctx), "error", err)
+	}
 
 	host := r.Host
 	if host == "" {
@@ -496,8 +469,7 @@ func shortenHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	shortURL := "http://" + host + "/" + code
 
-	// Hitung remaining quota
-	remaining := maxURLsPerSubnet - rateInfo.Count - 1 // -1 karena baru saja dibuat
+	remaining := effectiveLimit - newCount
 	if remaining < 0 {
 		remaining = 0
 	}
@@ -518,144 +490,132 @@ func shortenHandler(w http.ResponseWriter, r *http.Request) {
 		"rate_limit": map[string]any{
 			"remaining":      remaining,
 			"limit":          maxURLsPerSubnet,
-			"reset_in":       formatDuration(24*time.Hour - time.Since(rateInfo.FirstSeen)),
 			"current_subnet": clientSubnet,
 		},
 	})
 }
 
-func redirectHandler(code string, w http.ResponseWriter, r *http.Request) {
-	now := time.Now()
+// insertShortURL stores a new URLData document, either under the user-requested alias or
+// a freshly generated code. Generated codes retry on a collision (ErrCodeTaken); an alias
+// collision is returned as-is, since the user asked for that specific code.
+func insertShortURL(ctx context.Context, url, alias, clientIP, clientSubnet string, now, exp time.Time) (string, error) {
+	if alias != "" {
+		data := URLData{
+			Original:      url,
+			ShortCode:     alias,
+			CreatedAt:     now,
+			ExpiresAt:     exp,
+			CreatorIP:     clientIP,
+			CreatorSubnet: clientSubnet,
+		}
 
-	if c, ok := cache[code]; ok {
-		if now.Before(c.ExpiresAt) {
-			http.Redirect(w, r, c.OriginalURL, http.StatusFound)
-			return
-		} else {
-			delete(cache, code)
+		if err := appStore.Put(ctx, alias, data); err != nil {
+			return "", err
 		}
+
+		return alias, nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	for attempt := 0; attempt < maxGenCodeRetries; attempt++ {
+		code := genCode(codeLength)
+		data := URLData{
+			Original:      url,
+			ShortCode:     code,
+			CreatedAt:     now,
+			ExpiresAt:     exp,
+			CreatorIP:     clientIP,
+			CreatorSubnet: clientSubnet,
+		}
 
-	var u URLData
-	err := col.FindOne(ctx, bson.M{"short_code": code}).Decode(&u)
+		err := appStore.Put(ctx, code, data)
+		if err == nil {
+			return code, nil
+		}
 
-	if err == nil {
-		if now.Before(u.ExpiresAt) {
-			cache[code] = CacheItem{u.Original, u.ExpiresAt}
-			http.Redirect(w, r, u.Original, http.StatusFound)
-			return
-		} else {
-			col.DeleteOne(ctx, bson.M{"short_code": code})
+		if errors.Is(err, ErrCodeTaken) {
+			continue // collision, coba kode baru
 		}
+
+		return "", err
 	}
 
-	http.NotFound(w, r)
+	return "", fmt.Errorf("failed to generate a unique short code after %d attempts", maxGenCodeRetries)
 }
 
-func listHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	now := time.Now()
-	activeItems := make(map[string]CacheItem)
+func redirectHandler(code string, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
 
-	for code, item := range cache {
-		if now.Before(item.ExpiresAt) {
-			activeItems[code] = item
+	data, err := appStore.Get(ctx, code)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			logger.Error("error looking up short code", "request_id", requestIDFromContext(ctx), "short_code", code, "error", err)
 		}
+		http.NotFound(w, r)
+		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]any{
-		"count":       len(activeItems),
-		"items":       activeItems,
-		"server_time": now.Format(time.RFC3339),
-	})
-}
-
-func statsHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	if !data.BlockedAt.IsZero() {
+		writeInterstitial(w, code)
+		return
+	}
 
-	now := time.Now()
-	activeCount := 0
-	expiredInCache := 0
+	recordClick(code, r, getClientSubnet(r))
 
-	for _, item := range cache {
-		if now.Before(item.ExpiresAt) {
-			activeCount++
-		} else {
-			expiredInCache++
-		}
-	}
+	http.Redirect(w, r, data.Original, http.StatusFound)
+}
 
-	rateLimitMutex.RLock()
-	totalSubnets := len(rateLimitMap)
-	cooldownSubnets := 0
-	for _, info := range rateLimitMap {
-		if !info.Cooldown.IsZero() && now.Before(info.Cooldown) {
-			cooldownSubnets++
-		}
-	}
-	rateLimitMutex.RUnlock()
+func listHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	stats := map[string]any{
-		"server_time": now.Format(time.RFC3339),
-		"cache": map[string]int{
-			"total":   len(cache),
-			"active":  activeCount,
-			"expired": expiredInCache,
-		},
-		"rate_limit": map[string]any{
-			"max_per_subnet":        maxURLsPerSubnet,
-			"cooldown_hours":        cooldownHours,
-			"total_tracked_subnets": totalSubnets,
-			"subnets_in_cooldown":   cooldownSubnets,
-			"limit_based_on":        "subnet /24",
-		},
-		"cleanup_schedule": "every 1 hour",
-		"next_cleanup":     now.Add(1 * time.Hour).Format("15:04:05"),
+	lister, ok := appStore.(Lister)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(map[string]any{
+			"error": "listing is not supported by the current storage backend",
+		})
+		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	totalCount, err := col.CountDocuments(ctx, bson.M{})
-	if err == nil {
-		stats["database_total"] = totalCount
+	items, err := lister.List(ctx)
+	if err != nil {
+		http.Error(w, "internal server error", 500)
+		return
 	}
 
-	activeDBCount, err := col.CountDocuments(ctx, bson.M{
-		"expires_at": bson.M{"$gt": now},
+	json.NewEncoder(w).Encode(map[string]any{
+		"count":       len(items),
+		"items":       items,
+		"server_time": time.Now().Format(time.RFC3339),
 	})
-	if err == nil {
-		stats["database_active"] = activeDBCount
-	}
+}
 
-	// Hitung unique subnets
-	ctx2, cancel2 := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel2()
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	pipeline := []bson.M{
-		{
-			"$group": bson.M{
-				"_id":   "$creator_subnet",
-				"count": bson.M{"$sum": 1},
-			},
-		},
-		{
-			"$count": "unique_subnets",
+	now := time.Now()
+	stats := map[string]any{
+		"server_time": now.Format(time.RFC3339),
+		"backend":     storageBackend,
+		"rate_limit": map[string]any{
+			"max_per_subnet": maxURLsPerSubnet,
+			"cooldown_hours": cooldownHours,
+			"limit_based_on": "subnet /24",
 		},
+		"load": currentLoadStatus(),
 	}
 
-	cursor, err := col.Aggregate(ctx2, pipeline)
-	if err == nil {
-		defer cursor.Close(ctx2)
+	if sp, ok := appStore.(StatsProvider); ok {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
 
-		var result []bson.M
-		if cursor.All(ctx2, &result) == nil && len(result) > 0 {
-			if uniqueSubnets, ok := result[0]["unique_subnets"].(int32); ok {
-				stats["unique_creator_subnets"] = int(uniqueSubnets)
+		extra, err := sp.Stats(ctx)
+		if err == nil {
+			for k, v := range extra {
+				stats[k] = v
 			}
 		}
 	}
@@ -666,59 +626,53 @@ func statsHandler(w http.ResponseWriter, r *http.Request) {
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	rateLimitMutex.RLock()
-	rateLimitStats := len(rateLimitMap)
-	rateLimitMutex.RUnlock()
-
-	json.NewEncoder(w).Encode(map[string]any{
+	resp := map[string]any{
 		"status":              "ok",
-		"cache_len":           len(cache),
-		"rate_limit_subnets":  rateLimitStats,
+		"backend":             storageBackend,
 		"rate_limit_strategy": "per subnet /24",
 		"max_per_subnet":      maxURLsPerSubnet,
 		"server_time":         time.Now().Format(time.RFC3339),
-	})
-}
-
-func genCode(n int) string {
-	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	const digits = "0123456789"
-	const allChars = letters + digits
-
-	for {
-		// Generate random string
-		b := make([]byte, n)
-		for i := range b {
-			b[i] = allChars[rand.Intn(len(allChars))]
-		}
+		"load":                currentLoadStatus(),
+	}
 
-		code := string(b)
+	if sp, ok := appStore.(StatsProvider); ok {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
 
-		// Validasi: harus mengandung minimal 1 huruf dan 1 angka
-		if containsLetter(code) && containsDigit(code) {
-			return code
+		if extra, err := sp.Stats(ctx); err == nil {
+			for k, v := range extra {
+				resp[k] = v
+			}
 		}
-
-		// Jika tidak valid, coba lagi (sangat jarang terjadi untuk n=6)
 	}
+
+	json.NewEncoder(w).Encode(resp)
 }
 
-func containsLetter(s string) bool {
-	for _, c := range s {
-		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
-			return true
+// genCode generates a random short code of length n drawn from codeAlphabet using
+// crypto/rand, rejecting byte values that would bias the distribution towards the
+// start of the alphabet.
+func genCode(n int) string {
+	alphabetLen := len(codeAlphabet)
+	maxMultiple := (256 / alphabetLen) * alphabetLen
+
+	b := make([]byte, n)
+	buf := make([]byte, 1)
+	for i := 0; i < n; {
+		if _, err := rand.Read(buf); err != nil {
+			// crypto/rand kegagalan dianggap fatal, bukan sesuatu yang bisa di-retry diam-diam
+			fatal("crypto/rand read failed", "error", err)
 		}
-	}
-	return false
-}
 
-func containsDigit(s string) bool {
-	for _, c := range s {
-		if c >= '0' && c <= '9' {
-			return true
+		if int(buf[0]) >= maxMultiple {
+			continue // buang byte yang bias, coba lagi
 		}
+
+		b[i] = codeAlphabet[int(buf[0])%alphabetLen]
+		i++
 	}
-	return false
+
+	return string(b)
 }
 
 func formatDuration(d time.Duration) string {
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	initLogger()
+	os.Exit(m.Run())
+}
+
+func TestShortenAndRedirectHandler(t *testing.T) {
+	appStore = newMemoryStore()
+	codeLength = defaultCodeLength
+	codeAlphabet = defaultCodeAlphabet
+
+	reqBody, _ := json.Marshal(map[string]string{"url": "https://example.com/path"})
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(reqBody))
+	req.RemoteAddr = "203.0.113.10:1234"
+	rec := httptest.NewRecorder()
+
+	shortenHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("shortenHandler status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Success   bool   `json:"success"`
+		ShortCode string `json:"short_code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.Success || resp.ShortCode == "" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	redirectReq := httptest.NewRequest(http.MethodGet, "/"+resp.ShortCode, nil)
+	redirectRec := httptest.NewRecorder()
+
+	redirectHandler(resp.ShortCode, redirectRec, redirectReq)
+
+	if redirectRec.Code != http.StatusFound {
+		t.Fatalf("redirectHandler status = %d", redirectRec.Code)
+	}
+	if got := redirectRec.Header().Get("Location"); got != "https://example.com/path" {
+		t.Errorf("Location = %q, want %q", got, "https://example.com/path")
+	}
+}
+
+func TestShortenHandlerRejectsReservedAlias(t *testing.T) {
+	appStore = newMemoryStore()
+
+	reqBody, _ := json.Marshal(map[string]string{"url": "https://example.com", "alias": "health"})
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(reqBody))
+	req.RemoteAddr = "203.0.113.11:1234"
+	rec := httptest.NewRecorder()
+
+	shortenHandler(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d (reserved alias should be rejected)", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestRedirectHandlerUnknownCode(t *testing.T) {
+	appStore = newMemoryStore()
+
+	req := httptest.NewRequest(http.MethodGet, "/does-not-exist", nil)
+	rec := httptest.NewRecorder()
+
+	redirectHandler("does-not-exist", rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
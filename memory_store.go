@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a pure in-memory Store implementation. It keeps the service's behavior
+// observable without a MongoDB or Redis instance, so it's the backend used in tests.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	urls map[string]URLData
+
+	rateMu        sync.Mutex
+	rateCounts    map[string]int
+	rateWindowEnd map[string]time.Time
+}
+
+func newMemoryStore() Store {
+	return &MemoryStore{
+		urls:          make(map[string]URLData),
+		rateCounts:    make(map[string]int),
+		rateWindowEnd: make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, code string, data URLData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.urls[code]; ok && time.Now().Before(existing.ExpiresAt) {
+		return ErrCodeTaken
+	}
+
+	s.urls[code] = data
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, code string) (URLData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.urls[code]
+	if !ok || time.Now().After(data.ExpiresAt) {
+		return URLData{}, ErrNotFound
+	}
+
+	return data, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.urls, code)
+	return nil
+}
+
+func (s *MemoryStore) MarkBlocked(ctx context.Context, code string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.urls[code]
+	if !ok {
+		return ErrNotFound
+	}
+
+	data.BlockedAt = at
+	s.urls[code] = data
+	return nil
+}
+
+func (s *MemoryStore) CountBySubnetSince(ctx context.Context, subnet string, since time.Time) (int, time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	var earliest time.Time
+	for _, d := range s.urls {
+		if d.CreatorSubnet == subnet && d.CreatedAt.After(since) {
+			count++
+			if earliest.IsZero() || d.CreatedAt.Before(earliest) {
+				earliest = d.CreatedAt
+			}
+		}
+	}
+
+	if earliest.IsZero() {
+		return count, time.Time{}, nil
+	}
+	return count, earliest.Add(time.Since(since)), nil
+}
+
+func (s *MemoryStore) IncrRateLimit(ctx context.Context, subnet string, window time.Duration) (int, error) {
+	s.rateMu.Lock()
+	defer s.rateMu.Unlock()
+
+	now := time.Now()
+
+	if end, ok := s.rateWindowEnd[subnet]; !ok || now.After(end) {
+		s.rateCounts[subnet] = 0
+		s.rateWindowEnd[subnet] = now.Add(window)
+	}
+
+	s.rateCounts[subnet]++
+	return s.rateCounts[subnet], nil
+}
+
+func (s *MemoryStore) Close(ctx context.Context) error {
+	return nil
+}
+
+func (s *MemoryStore) CleanupExpired(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var removed int64
+	for code, d := range s.urls {
+		if now.After(d.ExpiresAt) {
+			delete(s.urls, code)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+func (s *MemoryStore) List(ctx context.Context) (map[string]URLData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	items := make(map[string]URLData, len(s.urls))
+	for code, d := range s.urls {
+		if now.Before(d.ExpiresAt) {
+			items[code] = d
+		}
+	}
+
+	return items, nil
+}
+
+func (s *MemoryStore) Stats(ctx context.Context) (map[string]any, error) {
+	s.mu.RLock()
+	total := len(s.urls)
+	s.mu.RUnlock()
+
+	s.rateMu.Lock()
+	trackedSubnets := len(s.rateCounts)
+	s.rateMu.Unlock()
+
+	return map[string]any{
+		"cache": map[string]int{
+			"total": total,
+		},
+		"rate_limit": map[string]any{
+			"total_tracked_subnets": trackedSubnets,
+		},
+	}, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStorePutGetDelete(t *testing.T) {
+	s := newMemoryStore()
+	ctx := context.Background()
+
+	data := URLData{Original: "https://example.com", ShortCode: "abc123", CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	if err := s.Put(ctx, "abc123", data); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if err := s.Put(ctx, "abc123", data); !errors.Is(err, ErrCodeTaken) {
+		t.Fatalf("Put duplicate = %v, want ErrCodeTaken", err)
+	}
+
+	got, err := s.Get(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Original != data.Original {
+		t.Errorf("Original = %q, want %q", got.Original, data.Original)
+	}
+
+	if err := s.Delete(ctx, "abc123"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, "abc123"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStoreGetExpired(t *testing.T) {
+	s := newMemoryStore()
+	ctx := context.Background()
+
+	data := URLData{
+		Original:  "https://example.com",
+		ShortCode: "expired",
+		CreatedAt: time.Now().Add(-2 * time.Hour),
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}
+	if err := s.Put(ctx, "expired", data); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if _, err := s.Get(ctx, "expired"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get expired = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStoreMarkBlocked(t *testing.T) {
+	s := newMemoryStore()
+	ctx := context.Background()
+
+	if err := s.MarkBlocked(ctx, "missing", time.Now()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("MarkBlocked unknown code = %v, want ErrNotFound", err)
+	}
+
+	data := URLData{Original: "https://example.com", ShortCode: "safe", CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	if err := s.Put(ctx, "safe", data); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if err := s.MarkBlocked(ctx, "safe", time.Now()); err != nil {
+		t.Fatalf("MarkBlocked: %v", err)
+	}
+
+	got, err := s.Get(ctx, "safe")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.BlockedAt.IsZero() {
+		t.Error("expected BlockedAt to be set after MarkBlocked")
+	}
+}
+
+func TestMemoryStoreRateLimit(t *testing.T) {
+	s := newMemoryStore()
+	ctx := context.Background()
+
+	const subnet = "10.0.0.0/24"
+	for i := 1; i <= 3; i++ {
+		count, err := s.IncrRateLimit(ctx, subnet, time.Hour)
+		if err != nil {
+			t.Fatalf("IncrRateLimit call %d: %v", i, err)
+		}
+		if count != i {
+			t.Errorf("IncrRateLimit call %d = %d, want %d", i, count, i)
+		}
+	}
+}
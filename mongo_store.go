@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// mongoDuplicateKeyCode is the MongoDB error code for a unique index violation (E11000).
+const mongoDuplicateKeyCode = 11000
+
+// rateLimitInfo tracks a subnet's request count and any active cooldown. It's kept
+// in-process rather than in Mongo itself, same as the original design, since it's
+// re-derived from the database on first sight of a subnet (see CountBySubnetSince).
+type rateLimitInfo struct {
+	Count     int
+	FirstSeen time.Time
+	Cooldown  time.Time
+}
+
+// MongoStore is the original, MongoDB-backed Store implementation. It keeps a small
+// in-process read cache in front of Mongo for the redirect hot path, and an in-process
+// rate-limit map mirroring the per-subnet counts and cooldowns recorded in Mongo.
+type MongoStore struct {
+	client *mongo.Client
+	col    *mongo.Collection
+
+	mu    sync.RWMutex
+	cache map[string]URLData // hot-path read cache in front of MongoDB
+
+	rateMu       sync.Mutex
+	rateLimitMap map[string]*rateLimitInfo
+}
+
+func newMongoStore(ctx context.Context) (Store, error) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		return nil, errors.New("MONGODB_URI not set in .env file or environment")
+	}
+
+	opts := options.Client().
+		ApplyURI(uri).
+		SetServerSelectionTimeout(10 * time.Second)
+
+	client, err := mongo.Connect(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(pingCtx, readpref.Primary()); err != nil {
+		return nil, err
+	}
+
+	s := &MongoStore{
+		client:       client,
+		col:          client.Database(dbName).Collection(collectionName),
+		cache:        make(map[string]URLData),
+		rateLimitMap: make(map[string]*rateLimitInfo),
+	}
+
+	s.ensureIndexes(ctx)
+	s.loadActiveCache(ctx)
+
+	logger.Info("mongodb connected successfully")
+	return s, nil
+}
+
+// ensureIndexes creates the unique index on short_code so a collision between two
+// concurrently generated codes (or a reused alias) is caught by Mongo (E11000) instead
+// of silently overwriting an existing record.
+func (s *MongoStore) ensureIndexes(ctx context.Context) {
+	idxCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := s.col.Indexes().CreateOne(idxCtx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "short_code", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		logger.Warn("failed to create unique index on short_code", "error", err)
+	}
+}
+
+func (s *MongoStore) loadActiveCache(ctx context.Context) {
+	loadCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	now := time.Now()
+
+	cursor, err := s.col.Find(loadCtx, bson.M{
+		"expires_at": bson.M{"$gt": now},
+	})
+	if err != nil {
+		logger.Error("error loading cache", "error", err)
+		return
+	}
+	defer cursor.Close(loadCtx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for cursor.Next(loadCtx) {
+		var u URLData
+		if cursor.Decode(&u) != nil {
+			continue
+		}
+
+		s.cache[u.ShortCode] = u
+	}
+
+	logger.Info("loaded active items to cache", "count", len(s.cache))
+}
+
+func (s *MongoStore) Put(ctx context.Context, code string, data URLData) error {
+	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := s.col.InsertOne(insertCtx, data); err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrCodeTaken
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	s.cache[code] = data
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *MongoStore) Get(ctx context.Context, code string) (URLData, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	item, ok := s.cache[code]
+	s.mu.RUnlock()
+
+	if ok {
+		if now.Before(item.ExpiresAt) {
+			return item, nil
+		}
+
+		s.mu.Lock()
+		delete(s.cache, code)
+		s.mu.Unlock()
+	}
+
+	findCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var u URLData
+	err := s.col.FindOne(findCtx, bson.M{"short_code": code}).Decode(&u)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return URLData{}, ErrNotFound
+		}
+		return URLData{}, err
+	}
+
+	if now.After(u.ExpiresAt) {
+		s.col.DeleteOne(findCtx, bson.M{"short_code": code})
+		return URLData{}, ErrNotFound
+	}
+
+	s.mu.Lock()
+	s.cache[code] = u
+	s.mu.Unlock()
+
+	return u, nil
+}
+
+// MarkBlocked flags code as unsafe in both Mongo and the in-process cache.
+func (s *MongoStore) MarkBlocked(ctx context.Context, code string, at time.Time) error {
+	updateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.col.UpdateOne(updateCtx,
+		bson.M{"short_code": code},
+		bson.M{"$set": bson.M{"blocked_at": at}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	s.mu.Lock()
+	if item, ok := s.cache[code]; ok {
+		item.BlockedAt = at
+		s.cache[code] = item
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *MongoStore) Delete(ctx context.Context, code string) error {
+	s.mu.Lock()
+	delete(s.cache, code)
+	s.mu.Unlock()
+
+	delCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := s.col.DeleteOne(delCtx, bson.M{"short_code": code})
+	return err
+}
+
+func (s *MongoStore) CountBySubnetSince(ctx context.Context, subnet string, since time.Time) (int, time.Time, error) {
+	s.rateMu.Lock()
+	defer s.rateMu.Unlock()
+
+	now := time.Now()
+
+	if info, exists := s.rateLimitMap[subnet]; exists {
+		if now.Sub(info.FirstSeen) >= 24*time.Hour {
+			info.Count = 0
+			info.FirstSeen = now
+			info.Cooldown = time.Time{}
+		}
+
+		if !info.Cooldown.IsZero() && now.Before(info.Cooldown) {
+			// Masih dalam cooldown: laporkan count sebagai sudah mencapai limit supaya
+			// pemanggil (yang membandingkan terhadap maxURLsPerSubnet) tetap menolak.
+			return maxURLsPerSubnet, info.Cooldown, nil
+		}
+
+		if !info.Cooldown.IsZero() && now.After(info.Cooldown) {
+			info.Cooldown = time.Time{}
+		}
+
+		return info.Count, info.FirstSeen.Add(24 * time.Hour), nil
+	}
+
+	countCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	count, err := s.col.CountDocuments(countCtx, bson.M{
+		"creator_subnet": subnet,
+		"created_at":     bson.M{"$gte": since},
+	})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	s.rateLimitMap[subnet] = &rateLimitInfo{Count: int(count), FirstSeen: now}
+
+	resetAt := now.Add(24 * time.Hour)
+	if int(count) >= maxURLsPerSubnet {
+		s.rateLimitMap[subnet].Cooldown = now.Add(cooldownHours * time.Hour)
+		resetAt = s.rateLimitMap[subnet].Cooldown
+	}
+
+	return int(count), resetAt, nil
+}
+
+func (s *MongoStore) IncrRateLimit(ctx context.Context, subnet string, window time.Duration) (int, error) {
+	s.rateMu.Lock()
+	defer s.rateMu.Unlock()
+
+	info, ok := s.rateLimitMap[subnet]
+	if !ok {
+		info = &rateLimitInfo{FirstSeen: time.Now()}
+		s.rateLimitMap[subnet] = info
+	}
+
+	info.Count++
+	if info.Count >= maxURLsPerSubnet {
+		info.Cooldown = time.Now().Add(cooldownHours * time.Hour)
+	}
+
+	return info.Count, nil
+}
+
+func (s *MongoStore) Close(ctx context.Context) error {
+	return s.client.Disconnect(ctx)
+}
+
+// MongoDatabase exposes the underlying MongoDB database for features that fall outside the
+// core Store interface, such as the click analytics collection.
+func (s *MongoStore) MongoDatabase() *mongo.Database {
+	return s.client.Database(dbName)
+}
+
+func (s *MongoStore) CleanupExpired(ctx context.Context) (int64, error) {
+	delCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+
+	result, err := s.col.DeleteMany(delCtx, bson.M{
+		"expires_at": bson.M{"$lt": now},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	for code, item := range s.cache {
+		if now.After(item.ExpiresAt) {
+			delete(s.cache, code)
+		}
+	}
+	s.mu.Unlock()
+
+	return result.DeletedCount, nil
+}
+
+func (s *MongoStore) CleanupRateLimits() {
+	s.rateMu.Lock()
+	defer s.rateMu.Unlock()
+
+	now := time.Now()
+	removedCount := 0
+
+	for subnet, info := range s.rateLimitMap {
+		if now.Sub(info.FirstSeen) > 24*time.Hour {
+			delete(s.rateLimitMap, subnet)
+			removedCount++
+		}
+	}
+
+	if removedCount > 0 {
+		logger.Info("rate limit cleanup removed old subnet entries", "removed", removedCount)
+	}
+}
+
+func (s *MongoStore) List(ctx context.Context) (map[string]URLData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	items := make(map[string]URLData, len(s.cache))
+	for code, item := range s.cache {
+		if now.Before(item.ExpiresAt) {
+			items[code] = item
+		}
+	}
+
+	return items, nil
+}
+
+func (s *MongoStore) Stats(ctx context.Context) (map[string]any, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	activeCount, expiredInCache := 0, 0
+	for _, item := range s.cache {
+		if now.Before(item.ExpiresAt) {
+			activeCount++
+		} else {
+			expiredInCache++
+		}
+	}
+	totalCache := len(s.cache)
+	s.mu.RUnlock()
+
+	s.rateMu.Lock()
+	totalSubnets := len(s.rateLimitMap)
+	cooldownSubnets := 0
+	for _, info := range s.rateLimitMap {
+		if !info.Cooldown.IsZero() && now.Before(info.Cooldown) {
+			cooldownSubnets++
+		}
+	}
+	s.rateMu.Unlock()
+
+	stats := map[string]any{
+		"cache": map[string]int{
+			"total":   totalCache,
+			"active":  activeCount,
+			"expired": expiredInCache,
+		},
+		"rate_limit": map[string]any{
+			"total_tracked_subnets": totalSubnets,
+			"subnets_in_cooldown":   cooldownSubnets,
+		},
+	}
+
+	statsCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if totalCount, err := s.col.CountDocuments(statsCtx, bson.M{}); err == nil {
+		stats["database_total"] = totalCount
+	}
+
+	if activeDBCount, err := s.col.CountDocuments(statsCtx, bson.M{"expires_at": bson.M{"$gt": now}}); err == nil {
+		stats["database_active"] = activeDBCount
+	}
+
+	aggCtx, cancel2 := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel2()
+
+	pipeline := []bson.M{
+		{"$group": bson.M{"_id": "$creator_subnet", "count": bson.M{"$sum": 1}}},
+		{"$count": "unique_subnets"},
+	}
+
+	cursor, err := s.col.Aggregate(aggCtx, pipeline)
+	if err == nil {
+		defer cursor.Close(aggCtx)
+
+		var result []bson.M
+		if cursor.All(aggCtx, &result) == nil && len(result) > 0 {
+			if uniqueSubnets, ok := result[0]["unique_subnets"].(int32); ok {
+				stats["unique_creator_subnets"] = int(uniqueSubnets)
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// isDuplicateKeyError reports whether err is a MongoDB E11000 unique index violation.
+func isDuplicateKeyError(err error) bool {
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) {
+		for _, we := range writeErr.WriteErrors {
+			if we.Code == mongoDuplicateKeyCode {
+				return true
+			}
+		}
+	}
+	return false
+}
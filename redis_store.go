@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore is a Store backed by Redis, so multiple instances of the service share one
+// view of reservations and rate limits instead of each keeping its own process-local map.
+// Short codes are reserved with SET NX EX (atomic "claim if free, expire with the link"),
+// and per-subnet rate limiting uses INCR+EXPIRE, Redis's standard fixed-window counter.
+type RedisStore struct {
+	rdb *redis.Client
+}
+
+func newRedisStore(ctx context.Context) (Store, error) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := rdb.Ping(pingCtx).Err(); err != nil {
+		return nil, fmt.Errorf("redis ping failed: %w", err)
+	}
+
+	return &RedisStore{rdb: rdb}, nil
+}
+
+func shortURLKey(code string) string    { return "kapiarso:url:" + code }
+func rateLimitKey(subnet string) string { return "kapiarso:ratelimit:" + subnet }
+
+func (s *RedisStore) Put(ctx context.Context, code string, data URLData) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(data.ExpiresAt)
+	if ttl <= 0 {
+		ttl = time.Second
+	}
+
+	ok, err := s.rdb.SetNX(ctx, shortURLKey(code), payload, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCodeTaken
+	}
+
+	return nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, code string) (URLData, error) {
+	raw, err := s.rdb.Get(ctx, shortURLKey(code)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return URLData{}, ErrNotFound
+	}
+	if err != nil {
+		return URLData{}, err
+	}
+
+	var data URLData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return URLData{}, err
+	}
+
+	return data, nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, code string) error {
+	return s.rdb.Del(ctx, shortURLKey(code)).Err()
+}
+
+func (s *RedisStore) MarkBlocked(ctx context.Context, code string, at time.Time) error {
+	key := shortURLKey(code)
+
+	data, err := s.Get(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	ttl, err := s.rdb.TTL(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		ttl = time.Until(data.ExpiresAt)
+	}
+
+	data.BlockedAt = at
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.Set(ctx, key, payload, ttl).Err()
+}
+
+// CountBySubnetSince ignores since: the window is fixed by the TTL set in IncrRateLimit,
+// the standard Redis fixed-window rate-limit pattern. resetAt is derived from that same TTL.
+func (s *RedisStore) CountBySubnetSince(ctx context.Context, subnet string, since time.Time) (int, time.Time, error) {
+	key := rateLimitKey(subnet)
+
+	count, err := s.rdb.Get(ctx, key).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, time.Time{}, nil
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	ttl, err := s.rdb.TTL(ctx, key).Result()
+	if err != nil || ttl < 0 {
+		return count, time.Time{}, nil
+	}
+
+	return count, time.Now().Add(ttl), nil
+}
+
+func (s *RedisStore) IncrRateLimit(ctx context.Context, subnet string, window time.Duration) (int, error) {
+	key := rateLimitKey(subnet)
+
+	count, err := s.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 1 {
+		if err := s.rdb.Expire(ctx, key, window).Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return int(count), nil
+}
+
+func (s *RedisStore) Close(ctx context.Context) error {
+	return s.rdb.Close()
+}
+
+func (s *RedisStore) Stats(ctx context.Context) (map[string]any, error) {
+	dbSize, err := s.rdb.DBSize(ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"redis": map[string]any{
+			"keys": dbSize,
+		},
+	}, nil
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	blocklistRefreshInterval = 1 * time.Hour
+	safeBrowsingCacheSize    = 1000
+	safeBrowsingCacheTTL     = 1 * time.Hour
+
+	// reportThreshold is how many distinct subnets must report a short code before it's
+	// actually blocked, so a single reporter can't vandalize someone else's link on a guess.
+	reportThreshold = 3
+
+	// reportsPerSubnetPerWindow caps how many reports one subnet can file in reportWindow,
+	// the same fixed-window shape as the per-subnet shorten rate limit.
+	reportsPerSubnetPerWindow = 5
+	reportWindow              = 24 * time.Hour
+
+	// reportCleanupInterval is how often stale reportTracker entries are pruned, the same
+	// cadence as the shorten rate limiter's cleanup (startRateLimitCleanup).
+	reportCleanupInterval = 1 * time.Hour
+)
+
+// URLScreener decides whether a URL is safe to shorten. A positive hit from any
+// implementation is enough to reject the request.
+type URLScreener interface {
+	// Screen returns blocked=true and the categories it matched on an unsafe URL.
+	Screen(ctx context.Context, rawURL string) (blocked bool, categories []string, err error)
+}
+
+// screener is the composite URLScreener wired up at startup; nil means screening is
+// disabled (no BLOCKLIST_PATH/BLOCKLIST_URL and no SAFE_BROWSING_API_KEY configured).
+var screener URLScreener
+
+// startURLScreening wires up the configured screener implementations. It's safe to call
+// even when nothing is configured: screener stays nil and shortenHandler skips the check.
+func startURLScreening() {
+	var screeners []URLScreener
+
+	if source := os.Getenv("BLOCKLIST_PATH"); source != "" {
+		bl := newBlocklistScreener(source)
+		bl.refresh()
+		bl.startAutoRefresh()
+		screeners = append(screeners, bl)
+	}
+
+	if apiKey := os.Getenv("SAFE_BROWSING_API_KEY"); apiKey != "" {
+		screeners = append(screeners, newSafeBrowsingScreener(apiKey))
+	}
+
+	if len(screeners) == 0 {
+		logger.Info("URL screening disabled (no BLOCKLIST_PATH or SAFE_BROWSING_API_KEY configured)")
+		return
+	}
+
+	screener = compositeScreener(screeners)
+	logger.Info("URL screening enabled", "screeners", len(screeners))
+}
+
+type compositeScreener []URLScreener
+
+func (c compositeScreener) Screen(ctx context.Context, rawURL string) (bool, []string, error) {
+	for _, s := range c {
+		blocked, categories, err := s.Screen(ctx, rawURL)
+		if err != nil {
+			logger.Warn("screener error, skipping", "error", err)
+			continue
+		}
+		if blocked {
+			return true, categories, nil
+		}
+	}
+	return false, nil, nil
+}
+
+// blocklistScreener rejects URLs whose host matches a locally-loaded set of blocked domain
+// hashes. The list is loaded from a local file or an HTTP(S) URL and refreshed hourly, the
+// same periodic-ticker pattern the cleanup jobs use.
+type blocklistScreener struct {
+	source string
+
+	mu     sync.RWMutex
+	hashes map[string]bool
+}
+
+func newBlocklistScreener(source string) *blocklistScreener {
+	return &blocklistScreener{source: source, hashes: make(map[string]bool)}
+}
+
+func (b *blocklistScreener) startAutoRefresh() {
+	ticker := time.NewTicker(blocklistRefreshInterval)
+	go func() {
+		for {
+			<-ticker.C
+			b.refresh()
+		}
+	}()
+}
+
+func (b *blocklistScreener) refresh() {
+	lines, err := b.load()
+	if err != nil {
+		logger.Warn("failed to refresh blocklist", "source", b.source, "error", err)
+		return
+	}
+
+	hashes := make(map[string]bool, len(lines))
+	for _, domain := range lines {
+		hashes[hashDomain(domain)] = true
+	}
+
+	b.mu.Lock()
+	b.hashes = hashes
+	b.mu.Unlock()
+
+	logger.Info("blocklist refreshed", "domains", len(hashes))
+}
+
+func (b *blocklistScreener) load() ([]string, error) {
+	if strings.HasPrefix(b.source, "http://") || strings.HasPrefix(b.source, "https://") {
+		resp, err := http.Get(b.source)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		return scanDomainLines(resp.Body)
+	}
+
+	f, err := os.Open(b.source)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return scanDomainLines(f)
+}
+
+func scanDomainLines(r io.Reader) ([]string, error) {
+	var domains []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, strings.ToLower(line))
+	}
+	return domains, scanner.Err()
+}
+
+func (b *blocklistScreener) Screen(ctx context.Context, rawURL string) (bool, []string, error) {
+	host, err := hostOf(rawURL)
+	if err != nil {
+		return false, nil, err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.hashes[hashDomain(host)] {
+		return true, []string{"blocklist"}, nil
+	}
+	return false, nil, nil
+}
+
+func hashDomain(domain string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(domain)))
+	return hex.EncodeToString(sum[:])
+}
+
+func hostOf(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	return u.Hostname(), nil
+}
+
+// safeBrowsingVerdict is a cached Safe Browsing result so repeated lookups for the same URL
+// within safeBrowsingCacheTTL don't count against the API quota.
+type safeBrowsingVerdict struct {
+	blocked    bool
+	categories []string
+	expiresAt  time.Time
+}
+
+// safeBrowsingScreener calls the Google Safe Browsing v4 threatMatches:find API, with an
+// in-memory LRU-ish cache (simple map + eviction on size) of recent verdicts.
+type safeBrowsingScreener struct {
+	apiKey string
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]safeBrowsingVerdict
+}
+
+func newSafeBrowsingScreener(apiKey string) *safeBrowsingScreener {
+	return &safeBrowsingScreener{
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 5 * time.Second},
+		cache:  make(map[string]safeBrowsingVerdict),
+	}
+}
+
+func (s *safeBrowsingScreener) Screen(ctx context.Context, rawURL string) (bool, []string, error) {
+	s.mu.Lock()
+	if v, ok := s.cache[rawURL]; ok && time.Now().Before(v.expiresAt) {
+		s.mu.Unlock()
+		return v.blocked, v.categories, nil
+	}
+	s.mu.Unlock()
+
+	blocked, categories, err := s.lookup(ctx, rawURL)
+	if err != nil {
+		return false, nil, err
+	}
+
+	s.mu.Lock()
+	if len(s.cache) >= safeBrowsingCacheSize {
+		// Bukan LRU murni: cukup buang satu entri acak untuk menjaga ukuran cache tetap
+		// kecil, masih jauh lebih murah daripada memanggil API lagi.
+		for k := range s.cache {
+			delete(s.cache, k)
+			break
+		}
+	}
+	s.cache[rawURL] = safeBrowsingVerdict{blocked: blocked, categories: categories, expiresAt: time.Now().Add(safeBrowsingCacheTTL)}
+	s.mu.Unlock()
+
+	return blocked, categories, nil
+}
+
+func (s *safeBrowsingScreener) lookup(ctx context.Context, rawURL string) (bool, []string, error) {
+	reqBody, err := json.Marshal(map[string]any{
+		"client": map[string]string{
+			"clientId":      "kapiarso",
+			"clientVersion": "1.0.0",
+		},
+		"threatInfo": map[string]any{
+			"threatTypes":      []string{"MALWARE", "SOCIAL_ENGINEERING", "UNWANTED_SOFTWARE"},
+			"platformTypes":    []string{"ANY_PLATFORM"},
+			"threatEntryTypes": []string{"URL"},
+			"threatEntries":    []map[string]string{{"url": rawURL}},
+		},
+	})
+	if err != nil {
+		return false, nil, err
+	}
+
+	endpoint := fmt.Sprintf("https://safebrowsing.googleapis.com/v4/threatMatches:find?key=%s", s.apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return false, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, nil, fmt.Errorf("safe browsing lookup failed: %s", resp.Status)
+	}
+
+	var result struct {
+		Matches []struct {
+			ThreatType string `json:"threatType"`
+		} `json:"matches"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, nil, err
+	}
+
+	if len(result.Matches) == 0 {
+		return false, nil, nil
+	}
+
+	categories := make([]string, 0, len(result.Matches))
+	for _, m := range result.Matches {
+		categories = append(categories, m.ThreatType)
+	}
+	return true, categories, nil
+}
+
+// reportTracker gates POST /api/report so reporting can't be used to instantly vandalize
+// someone else's link: a subnet can only file so many reports per day, and a code is only
+// actually blocked once reportThreshold distinct subnets have flagged it (a lightweight
+// pending-review state instead of a single report triggering MarkBlocked).
+type reportTracker struct {
+	mu sync.Mutex
+
+	// reportersByCode tracks which subnets have reported each code, so repeat reports from
+	// the same subnet don't count twice towards reportThreshold. Entries older than
+	// reportWindow are pruned by cleanup, the same fixed-window shape as subnetWindow below.
+	reportersByCode map[string]*codeReports
+
+	// subnetWindow is the per-subnet report rate limit, same fixed-window shape as the
+	// shorten rate limiter.
+	subnetWindow map[string]*rateLimitInfo
+}
+
+// codeReports is the pending-report state for a single short code: which subnets have
+// flagged it, and when the first report came in (used to age the entry out).
+type codeReports struct {
+	subnets       map[string]bool
+	firstReportAt time.Time
+}
+
+var reports = &reportTracker{
+	reportersByCode: make(map[string]*codeReports),
+	subnetWindow:    make(map[string]*rateLimitInfo),
+}
+
+// register records subnet's report of code and returns the number of distinct subnets that
+// have now reported it, or ok=false if subnet has exceeded its own report rate limit.
+func (t *reportTracker) register(code, subnet string) (count int, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+
+	info, exists := t.subnetWindow[subnet]
+	if !exists || now.Sub(info.FirstSeen) >= reportWindow {
+		info = &rateLimitInfo{FirstSeen: now}
+		t.subnetWindow[subnet] = info
+	}
+	if info.Count >= reportsPerSubnetPerWindow {
+		return 0, false
+	}
+	info.Count++
+
+	reporters, exists := t.reportersByCode[code]
+	if !exists {
+		reporters = &codeReports{subnets: make(map[string]bool), firstReportAt: now}
+		t.reportersByCode[code] = reporters
+	}
+	reporters.subnets[subnet] = true
+
+	return len(reporters.subnets), true
+}
+
+// resolve drops code's pending-report state once it's been decided (blocked, or found to no
+// longer exist), so a resolved code doesn't keep occupying memory.
+func (t *reportTracker) resolve(code string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.reportersByCode, code)
+}
+
+// cleanup prunes subnetWindow and reportersByCode entries older than reportWindow, so a
+// reporter (or a batch of short-lived codes) that never comes back doesn't grow the maps
+// forever. Mirrors cleanupRateLimitMap's age-out-by-FirstSeen approach.
+func (t *reportTracker) cleanup() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	removedSubnets, removedCodes := 0, 0
+
+	for subnet, info := range t.subnetWindow {
+		if now.Sub(info.FirstSeen) > reportWindow {
+			delete(t.subnetWindow, subnet)
+			removedSubnets++
+		}
+	}
+
+	for code, reporters := range t.reportersByCode {
+		if now.Sub(reporters.firstReportAt) > reportWindow {
+			delete(t.reportersByCode, code)
+			removedCodes++
+		}
+	}
+
+	if removedSubnets > 0 || removedCodes > 0 {
+		logger.Info("report tracker cleanup removed stale entries", "subnets", removedSubnets, "codes", removedCodes)
+	}
+}
+
+// startReportTrackerCleanup periodically ages out stale reportTracker state, the same
+// ticker-goroutine pattern as startRateLimitCleanup.
+func startReportTrackerCleanup() {
+	ticker := time.NewTicker(reportCleanupInterval)
+
+	go func() {
+		for {
+			<-ticker.C
+			reports.cleanup()
+		}
+	}()
+
+	logger.Info("report tracker cleanup scheduled", "interval", reportCleanupInterval.String())
+}
+
+// reportHandler serves POST /api/report, letting users flag an existing short code as
+// unsafe. A single report only registers as pending; the code is actually marked blocked_at
+// (so redirectHandler shows the interstitial) once reportThreshold distinct subnets agree.
+func reportHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var body struct {
+		ShortCode string `json:"short_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ShortCode == "" {
+		http.Error(w, "bad request", 400)
+		return
+	}
+
+	if len(body.ShortCode) > maxRoutablePathLen() || !isValidShortCodePath(body.ShortCode) {
+		http.Error(w, "bad request", 400)
+		return
+	}
+
+	subnet := getClientSubnet(r)
+
+	count, ok := reports.register(body.ShortCode, subnet)
+	if !ok {
+		w.Header().Set("Retry-After", "86400")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]any{
+			"success": false,
+			"error":   "report_rate_limit_exceeded",
+		})
+		return
+	}
+
+	if count < reportThreshold {
+		json.NewEncoder(w).Encode(map[string]any{
+			"success":    true,
+			"short_code": body.ShortCode,
+			"status":     "pending",
+			"reports":    count,
+			"threshold":  reportThreshold,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := appStore.MarkBlocked(ctx, body.ShortCode, time.Now()); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			reports.resolve(body.ShortCode)
+			http.Error(w, "short code not found", 404)
+			return
+		}
+		logger.Error("error marking short code as blocked", "request_id", requestIDFromContext(ctx), "short_code", body.ShortCode, "error", err)
+		http.Error(w, "internal server error", 500)
+		return
+	}
+
+	reports.resolve(body.ShortCode)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":    true,
+		"short_code": body.ShortCode,
+		"status":     "blocked",
+		"blocked_at": time.Now().Format(time.RFC3339),
+	})
+}
+
+const interstitialTemplate = `<!DOCTYPE html>
+<html>
+<head><title>Peringatan - Tautan Diblokir</title></head>
+<body>
+<h1>Tautan ini telah dilaporkan sebagai tidak aman</h1>
+<p>Short code <strong>%s</strong> mengarah ke URL yang telah diblokir dan tidak akan diteruskan secara otomatis.</p>
+</body>
+</html>`
+
+func writeInterstitial(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+	fmt.Fprintf(w, interstitialTemplate, html.EscapeString(code))
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Store abstracts short-URL persistence and per-subnet rate limiting so the server can run
+// against MongoDB, Redis, or a pure in-memory backend (selected via STORAGE_BACKEND) without
+// the request handlers knowing which one is active. Previously the globals client, col,
+// cache, and rateLimitMap were process-local, so two replicas behind a load balancer each
+// kept their own view of rate limits and recently-written redirects; a shared backend like
+// Redis fixes that, while the in-memory backend keeps tests free of external dependencies.
+type Store interface {
+	// Put reserves code for the given URLData. It returns ErrCodeTaken if code is already
+	// in use by a non-expired record.
+	Put(ctx context.Context, code string, data URLData) error
+
+	// Get returns the URLData for code, or ErrNotFound if it doesn't exist or has expired.
+	Get(ctx context.Context, code string) (URLData, error)
+
+	// Delete removes code from the store.
+	Delete(ctx context.Context, code string) error
+
+	// MarkBlocked flags code as unsafe as of the given time, so Get keeps returning the
+	// record (redirectHandler needs it to render the interstitial warning) but with
+	// BlockedAt set. Returns ErrNotFound if code doesn't exist.
+	MarkBlocked(ctx context.Context, code string, at time.Time) error
+
+	// CountBySubnetSince returns how many URLs a subnet has created since the given time,
+	// along with resetAt: the time at which that count will naturally drop off (the start
+	// of the subnet's current window plus the rate-limit window length). Callers use resetAt
+	// to report an accurate cooldown remaining instead of assuming the full window length.
+	CountBySubnetSince(ctx context.Context, subnet string, since time.Time) (count int, resetAt time.Time, err error)
+
+	// IncrRateLimit atomically increments subnet's request counter for the current window
+	// (starting a fresh window with the given TTL if none is active) and returns the new count.
+	IncrRateLimit(ctx context.Context, subnet string, window time.Duration) (int, error)
+
+	// Close releases any resources (connections, tickers) held by the store.
+	Close(ctx context.Context) error
+}
+
+// ExpiryCleaner is implemented by stores whose records don't expire on their own (MongoDB)
+// and therefore need the periodic sweep that performCleanup/initialCleanup run. Backends
+// with native per-key TTLs (Redis, and the in-memory store for tests) don't implement this.
+type ExpiryCleaner interface {
+	CleanupExpired(ctx context.Context) (int64, error)
+}
+
+// RateLimitCleaner is implemented by stores that keep their own in-process rate-limit state
+// and need it pruned periodically (MongoDB). Backends where IncrRateLimit's window carries
+// its own TTL (Redis) don't implement this.
+type RateLimitCleaner interface {
+	CleanupRateLimits()
+}
+
+// Lister is implemented by stores that can cheaply enumerate their active entries, backing
+// the debug /api/list endpoint. Redis has no efficient way to do this without a key-pattern
+// scan, so it intentionally doesn't implement it.
+type Lister interface {
+	List(ctx context.Context) (map[string]URLData, error)
+}
+
+// StatsProvider is implemented by stores that can report backend-specific diagnostics for
+// the /health and /api/stats endpoints.
+type StatsProvider interface {
+	Stats(ctx context.Context) (map[string]any, error)
+}
+
+var (
+	// ErrCodeTaken is returned by Put when code already exists and hasn't expired.
+	ErrCodeTaken = errors.New("store: code already taken")
+	// ErrNotFound is returned by Get when code doesn't exist or has expired.
+	ErrNotFound = errors.New("store: not found")
+)
+
+// storageBackend records which backend newStore selected, for reporting in /health.
+var storageBackend string
+
+// newStore builds the Store selected by the STORAGE_BACKEND env var ("mongo", "redis",
+// "memory"). Defaults to "mongo" to match the service's historical behavior.
+func newStore(ctx context.Context) (Store, error) {
+	storageBackend = os.Getenv("STORAGE_BACKEND")
+
+	switch storageBackend {
+	case "", "mongo":
+		storageBackend = "mongo"
+		return newMongoStore(ctx)
+	case "redis":
+		return newRedisStore(ctx)
+	case "memory":
+		return newMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", storageBackend)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewStoreMemoryBackend(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	s, err := newStore(context.Background())
+	if err != nil {
+		t.Fatalf("newStore: %v", err)
+	}
+	if _, ok := s.(*MemoryStore); !ok {
+		t.Fatalf("newStore returned %T, want *MemoryStore", s)
+	}
+}
+
+func TestNewStoreUnknownBackend(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "bogus")
+
+	if _, err := newStore(context.Background()); err == nil {
+		t.Fatal("newStore with unknown STORAGE_BACKEND: expected an error, got nil")
+	}
+}
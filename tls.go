@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// acmeManager is non-nil when ACME_DOMAINS is configured, so startServer and certHandler
+// know TLS is being served via autocert rather than plain HTTP.
+var acmeManager *autocert.Manager
+
+var (
+	lastCertMu sync.RWMutex
+	lastCert   *x509.Certificate
+)
+
+// maybeStartTLSServer serves HTTPS with automatic Let's Encrypt certificates when
+// ACME_DOMAINS is set, falling back to the plain HTTP server otherwise. When TLS is active,
+// :80 still listens, but only to redirect to HTTPS and to answer ACME's HTTP-01 challenge.
+func maybeStartTLSServer(handler http.Handler) bool {
+	domainsEnv := os.Getenv("ACME_DOMAINS")
+	if domainsEnv == "" {
+		return false
+	}
+
+	domains := strings.Split(domainsEnv, ",")
+	for i := range domains {
+		domains[i] = strings.TrimSpace(domains[i])
+	}
+
+	cacheDir := os.Getenv("ACME_CACHE_DIR")
+	if cacheDir == "" {
+		cacheDir = "./acme-cache"
+	}
+
+	acmeManager = &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      autocert.DirCache(cacheDir),
+	}
+
+	httpServer := &http.Server{
+		Addr:    ":80",
+		Handler: acmeManager.HTTPHandler(http.HandlerFunc(redirectToHTTPS)),
+	}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil {
+			logger.Warn("ACME HTTP challenge/redirect server stopped", "error", err)
+		}
+	}()
+
+	httpsServer := &http.Server{
+		Addr:      ":443",
+		Handler:   handler,
+		TLSConfig: withCertTracking(acmeManager.TLSConfig()),
+	}
+
+	logger.Info("server running with ACME-managed TLS", "domains", strings.Join(domains, ", "))
+
+	if err := httpsServer.ListenAndServeTLS("", ""); err != nil {
+		fatal("HTTPS server failed to start", "error", err)
+	}
+
+	return true
+}
+
+func redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	target := "https://" + r.Host + r.URL.RequestURI()
+	http.Redirect(w, r, target, http.StatusMovedPermanently)
+}
+
+// withCertTracking wraps the autocert TLSConfig's GetCertificate so the most recently
+// served certificate is cached for certHandler to report on, the same way the rest of the
+// service exposes runtime state via /health and /api/stats.
+func withCertTracking(cfg *tls.Config) *tls.Config {
+	inner := cfg.GetCertificate
+
+	cfg.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, err := inner(hello)
+		if err != nil || cert == nil || len(cert.Certificate) == 0 {
+			return cert, err
+		}
+
+		if leaf, parseErr := x509.ParseCertificate(cert.Certificate[0]); parseErr == nil {
+			lastCertMu.Lock()
+			lastCert = leaf
+			lastCertMu.Unlock()
+		}
+
+		return cert, nil
+	}
+
+	return cfg
+}
+
+// certHandler serves GET /api/cert, reporting the expiry and issuer of the most recently
+// served certificate. Protected by a bearer token (CERT_ADMIN_TOKEN) since it's an
+// operator-facing admin endpoint, not public API surface like /health.
+func certHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !isAuthorizedAdmin(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	lastCertMu.RLock()
+	cert := lastCert
+	lastCertMu.RUnlock()
+
+	if cert == nil {
+		json.NewEncoder(w).Encode(map[string]any{
+			"tls_enabled": acmeManager != nil,
+			"status":      "no certificate served yet",
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"tls_enabled": true,
+		"subject":     cert.Subject.CommonName,
+		"issuer":      cert.Issuer.CommonName,
+		"not_before":  cert.NotBefore,
+		"not_after":   cert.NotAfter,
+		"dns_names":   cert.DNSNames,
+	})
+}
+
+func isAuthorizedAdmin(r *http.Request) bool {
+	token := os.Getenv("CERT_ADMIN_TOKEN")
+	if token == "" {
+		return false
+	}
+
+	auth := r.Header.Get("Authorization")
+	expected := "Bearer " + token
+
+	// Constant-time compare: a naive == leaks how many leading bytes matched through
+	// response timing, letting an attacker brute-force the admin token byte by byte.
+	return subtle.ConstantTimeCompare([]byte(auth), []byte(expected)) == 1
+}